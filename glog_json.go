@@ -206,7 +206,9 @@ func (j *Json) Strs(name string, ss []string) *Json {
 func (j *Json) Msg(s string) {
 	if j != nil {
 		if s != "" {
-			j.p = append(j.p, ",\"message\":"...)
+			j.p = append(j.p, ',', '"')
+			j.p = append(j.p, messageKey()...)
+			j.p = append(j.p, '"', ':')
 			j.string(s, false)
 		}
 		j.p = append(j.p, '}', '\n')
@@ -215,7 +217,7 @@ func (j *Json) Msg(s string) {
 }
 
 func (j *Json) Msgf(format string, v ...interface{}) {
-	if j == nil {
+	if j != nil {
 		j.Msg(fmt.Sprintf(format, v...))
 	}
 }
@@ -238,7 +240,7 @@ func json(s severity, level Level) *Json {
 	}
 
 	if logging.verbosity.get() >= level {
-		return jheader(s, level, file, line)
+		return sampledHeader(s, level, file, line)
 	}
 
 	if atomic.LoadInt32(&logging.filterLength) > 0 {
@@ -256,7 +258,7 @@ func json(s severity, level Level) *Json {
 		}
 
 		if v >= level {
-			return jheader(s, level, file, line)
+			return sampledHeader(s, level, file, line)
 		}
 	}
 
@@ -273,6 +275,11 @@ func jheader(s severity, level Level, file string, line int) *Json {
 	now := timeNow()
 	j := jbufpool.Get().(*Json)
 
+	if sc := activeSchema(); sc != nil {
+		j.p = sc.Header(s, level, file, line, now)
+		return j
+	}
+
 	year, month, day := now.Date()
 	hour, minute, second := now.Clock()
 	_, offset := now.Zone()
@@ -348,6 +355,7 @@ func jheader(s severity, level Level, file string, line int) *Json {
 }
 
 func (l *loggingT) outputj(s severity, buf *Json, file string, line int, alsoToStderr bool) {
+	resolveSink()
 	l.mu.Lock()
 	if l.traceLocation.isSet() {
 		if l.traceLocation.match(file, line) {
@@ -364,25 +372,17 @@ func (l *loggingT) outputj(s severity, buf *Json, file string, line int, alsoToS
 		if alsoToStderr || l.alsoToStderr || s >= l.stderrThreshold.get() {
 			os.Stderr.Write(data)
 		}
-		if l.file[s] == nil {
-			if err := l.createFiles(s); err != nil {
-				os.Stderr.Write(data) // Make sure the message appears somewhere.
+		sink := currentSink()
+		usingDefaultSink := sink == nil
+		if usingDefaultSink {
+			sink = &fileSink{l: l}
+		}
+		if err := sink.Write(s, data); err != nil {
+			os.Stderr.Write(data) // Make sure the message appears somewhere.
+			if usingDefaultSink {
 				l.exit(err)
 			}
 		}
-		switch s {
-		case fatalLog:
-			l.file[fatalLog].Write(data)
-			fallthrough
-		case errorLog:
-			l.file[errorLog].Write(data)
-			fallthrough
-		case warningLog:
-			l.file[warningLog].Write(data)
-			fallthrough
-		case infoLog:
-			l.file[infoLog].Write(data)
-		}
 	}
 	if s == fatalLog {
 		// If we got here via Exit rather than Fatal, print no stacks.
@@ -0,0 +1,75 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Context-aware JSON entry points, for correlating log lines with
+// whatever request/trace identifiers a caller carries on a
+// context.Context. The core package stays free of any tracing
+// dependency; see glog_json_otel.go for an OpenTelemetry extractor
+// built behind the "otel" build tag.
+
+package glog
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ContextExtractor pulls correlation fields (trace_id, span_id, request
+// id, ...) out of ctx. It is invoked for every JInfoCtx/JWarningCtx/
+// JErrorCtx/JFatalCtx call that survives the severity/verbosity filter.
+type ContextExtractor func(ctx context.Context) []Field
+
+var contextExtractor atomic.Value // holds ContextExtractor
+
+// SetContextExtractor installs fn as the extractor used by the *Ctx entry
+// points. Passing nil disables extraction.
+func SetContextExtractor(fn ContextExtractor) {
+	contextExtractor.Store(fn)
+}
+
+func getContextExtractor() ContextExtractor {
+	fn, _ := contextExtractor.Load().(ContextExtractor)
+	return fn
+}
+
+// JInfoCtx is JInfo with correlation fields from ctx emitted as top-level
+// keys ahead of any fields the caller adds.
+func JInfoCtx(ctx context.Context) *Json {
+	return json(infoLog, 0).withContext(ctx)
+}
+
+func JWarningCtx(ctx context.Context) *Json {
+	return json(warningLog, 0).withContext(ctx)
+}
+
+func JErrorCtx(ctx context.Context) *Json {
+	return json(errorLog, 0).withContext(ctx)
+}
+
+func JFatalCtx(ctx context.Context) *Json {
+	return json(fatalLog, 0).withContext(ctx)
+}
+
+func (j *Json) withContext(ctx context.Context) *Json {
+	if j == nil || ctx == nil {
+		return j
+	}
+	extractor := getContextExtractor()
+	if extractor == nil {
+		return j
+	}
+	return j.With(extractor(ctx)...)
+}
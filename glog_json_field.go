@@ -0,0 +1,183 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Field is a structured key/value pair for the Json builder, analogous
+// to the field helpers found in zap/zerolog.
+
+package glog
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// FieldType tags the kind of value carried by a Field so that With can
+// dispatch straight to the typed fast paths in glog_json.go instead of
+// going through interface{}.
+type FieldType int
+
+const (
+	IntFieldType FieldType = iota
+	UintFieldType
+	FloatFieldType
+	BoolFieldType
+	StringFieldType
+	DurationFieldType
+	TimeFieldType
+	ErrorFieldType
+	StringerFieldType
+	BytesFieldType
+	LazyStringFieldType
+	AnyFieldType
+)
+
+// Field is a single structured log attribute produced by one of the
+// typed constructors below (Int, Str, Error, ...) and consumed by
+// (*Json).With.
+type Field struct {
+	Key   string
+	Type  FieldType
+	Int   int64
+	Float float64
+	Str   string
+	Iface interface{}
+}
+
+func Int(key string, i int) Field      { return Field{Key: key, Type: IntFieldType, Int: int64(i)} }
+func Int64(key string, i int64) Field  { return Field{Key: key, Type: IntFieldType, Int: i} }
+func Uint(key string, i uint) Field    { return Field{Key: key, Type: UintFieldType, Int: int64(i)} }
+func Uint64(key string, i uint64) Field { return Field{Key: key, Type: UintFieldType, Int: int64(i)} }
+
+func Float32(key string, f float32) Field {
+	return Field{Key: key, Type: FloatFieldType, Float: float64(f)}
+}
+func Float64(key string, f float64) Field { return Field{Key: key, Type: FloatFieldType, Float: f} }
+
+func Bool(key string, b bool) Field {
+	i := int64(0)
+	if b {
+		i = 1
+	}
+	return Field{Key: key, Type: BoolFieldType, Int: i}
+}
+
+func Str(key, s string) Field { return Field{Key: key, Type: StringFieldType, Str: s} }
+
+func Dur(key string, d time.Duration) Field {
+	return Field{Key: key, Type: DurationFieldType, Int: int64(d)}
+}
+
+func Time(key string, t time.Time) Field {
+	return Field{Key: key, Type: TimeFieldType, Iface: t}
+}
+
+// Error builds a Field carrying err under the "error" key, matching the
+// key already used by (*Json).Err.
+func Error(err error) Field {
+	return Field{Key: "error", Type: ErrorFieldType, Iface: err}
+}
+
+func Stringer(key string, s fmt.Stringer) Field {
+	return Field{Key: key, Type: StringerFieldType, Iface: s}
+}
+
+// Bytes carries an arbitrary byte slice (hashes, protobufs, ...). With
+// base64-encodes it so non-UTF-8 payloads survive losslessly instead of
+// being mangled by the JSON string escaper.
+func Bytes(key string, b []byte) Field {
+	return Field{Key: key, Type: BytesFieldType, Iface: b}
+}
+
+// LazyStr defers fn until the entry actually clears the severity/verbosity
+// filter, so expensive string construction is skipped for suppressed
+// log lines. Msgf also skips its formatting on a suppressed line, but
+// the caller still evaluates its arguments.
+func LazyStr(key string, fn func() string) Field {
+	return Field{Key: key, Type: LazyStringFieldType, Iface: fn}
+}
+
+// Any falls back to fmt formatting for values with no typed constructor.
+func Any(key string, v interface{}) Field {
+	return Field{Key: key, Type: AnyFieldType, Iface: v}
+}
+
+// With appends fields to j using the typed fast path for each field's
+// FieldType. Like every other Json method it is a no-op on a nil
+// receiver, which is also what keeps LazyStr closures from running on a
+// filtered-out entry: the switch below is never reached.
+func (j *Json) With(fields ...Field) *Json {
+	if j == nil {
+		return j
+	}
+	for _, f := range fields {
+		switch f.Type {
+		case IntFieldType:
+			j.Int64(f.Key, f.Int)
+		case UintFieldType:
+			j.Uint64(f.Key, uint64(f.Int))
+		case FloatFieldType:
+			j.Float64(f.Key, f.Float)
+		case BoolFieldType:
+			j.Bool(f.Key, f.Int != 0)
+		case StringFieldType:
+			j.Str(f.Key, f.Str)
+		case DurationFieldType:
+			j.Str(f.Key, time.Duration(f.Int).String())
+		case TimeFieldType:
+			if t, ok := f.Iface.(time.Time); ok {
+				j.Str(f.Key, t.Format(time.RFC3339Nano))
+			}
+		case ErrorFieldType:
+			if err, ok := f.Iface.(error); ok && err != nil {
+				j.Err(err)
+			}
+		case StringerFieldType:
+			if s, ok := f.Iface.(fmt.Stringer); ok {
+				j.Str(f.Key, s.String())
+			}
+		case BytesFieldType:
+			if b, ok := f.Iface.([]byte); ok {
+				j.Str(f.Key, base64.StdEncoding.EncodeToString(b))
+			}
+		case LazyStringFieldType:
+			if fn, ok := f.Iface.(func() string); ok {
+				j.Str(f.Key, fn())
+			}
+		case AnyFieldType:
+			j.Str(f.Key, fmt.Sprintf("%v", f.Iface))
+		}
+	}
+	return j
+}
+
+// JInfow logs msg at info severity with the given fields, analogous to
+// the *w (structured) shortcuts in zap's SugaredLogger.
+func JInfow(msg string, fields ...Field) {
+	json(infoLog, 0).With(fields...).Msg(msg)
+}
+
+func JWarningw(msg string, fields ...Field) {
+	json(warningLog, 0).With(fields...).Msg(msg)
+}
+
+func JErrorw(msg string, fields ...Field) {
+	json(errorLog, 0).With(fields...).Msg(msg)
+}
+
+func JFatalw(msg string, fields ...Field) {
+	json(fatalLog, 0).With(fields...).Msg(msg)
+}
@@ -0,0 +1,187 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// GRPCSink batches JSON log entries and ships them to a remote
+// log-collector service over the streaming RPC:
+//
+//	service LogCollector {
+//	  rpc Upsert(stream LogEntry) returns (Ack);
+//	}
+//
+// glog itself stays free of a grpc/protobuf dependency: callers generate
+// their own client stub from that proto and hand GRPCSink a thin
+// LogStreamClient adapter around it, the same way database/sql takes a
+// driver rather than vendoring one.
+
+package glog
+
+import (
+	"sync"
+	"time"
+)
+
+// LogEntry is the wire record GRPCSink sends for each buffered log line.
+type LogEntry struct {
+	Severity severity
+	Time     time.Time
+	Payload  []byte
+}
+
+// LogStreamClient adapts a generated Upsert client stream. Send pushes
+// one batch; CloseAndRecv finishes the stream and waits for the Ack.
+type LogStreamClient interface {
+	Send(entries []LogEntry) error
+	CloseAndRecv() error
+}
+
+// Dialer opens a fresh LogStreamClient, e.g. by dialing the collector
+// and calling the generated LogCollectorClient.Upsert method.
+type Dialer func() (LogStreamClient, error)
+
+// GRPCSink batches entries and flushes them to a LogStreamClient created
+// by dial, reconnecting with exponential backoff when the stream breaks.
+type GRPCSink struct {
+	dial          Dialer
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	pending []LogEntry
+	client  LogStreamClient
+	bo      backoff
+
+	wake   chan struct{}
+	closed chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewGRPCSink starts a background goroutine that flushes every
+// flushInterval or once batchSize entries have accumulated, whichever
+// comes first. Dialing, sending and backoff sleeping happen on that
+// goroutine, except for an explicit Flush call, which flushes on the
+// caller's goroutine. Write only ever enqueues, so a stalled collector
+// cannot block the caller (outputj calls Write while holding loggingT.mu).
+func NewGRPCSink(dial Dialer, batchSize int, flushInterval time.Duration) *GRPCSink {
+	g := &GRPCSink{
+		dial:          dial,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		bo:            backoff{base: 100 * time.Millisecond, max: 30 * time.Second},
+		wake:          make(chan struct{}, 1),
+		closed:        make(chan struct{}),
+	}
+	g.wg.Add(1)
+	go g.loop()
+	return g
+}
+
+func (g *GRPCSink) loop() {
+	defer g.wg.Done()
+	t := time.NewTicker(g.flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			g.flush()
+		case <-g.wake:
+			g.flush()
+		case <-g.closed:
+			g.flush()
+			return
+		}
+	}
+}
+
+// Write only ever enqueues and nudges loop; it must never dial, send or
+// sleep on the caller's goroutine.
+func (g *GRPCSink) Write(sev severity, entry []byte) error {
+	payload := make([]byte, len(entry))
+	copy(payload, entry)
+
+	g.mu.Lock()
+	g.pending = append(g.pending, LogEntry{Severity: sev, Time: timeNow(), Payload: payload})
+	full := len(g.pending) >= g.batchSize
+	g.mu.Unlock()
+
+	if full {
+		select {
+		case g.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (g *GRPCSink) flush() {
+	g.mu.Lock()
+	if len(g.pending) == 0 {
+		g.mu.Unlock()
+		return
+	}
+	batch := g.pending
+	g.pending = nil
+	client := g.client
+	g.mu.Unlock()
+
+	if client == nil {
+		var err error
+		client, err = g.dial()
+		if err != nil {
+			time.Sleep(g.bo.next())
+			g.requeue(batch)
+			return
+		}
+		g.bo.reset()
+		g.mu.Lock()
+		g.client = client
+		g.mu.Unlock()
+	}
+
+	if err := client.Send(batch); err != nil {
+		g.mu.Lock()
+		g.client = nil
+		g.mu.Unlock()
+		g.requeue(batch)
+	}
+}
+
+// requeue puts a failed batch back at the front of the queue so a
+// reconnect retries it rather than silently losing entries.
+func (g *GRPCSink) requeue(batch []LogEntry) {
+	g.mu.Lock()
+	g.pending = append(batch, g.pending...)
+	g.mu.Unlock()
+}
+
+func (g *GRPCSink) Flush() error {
+	g.flush()
+	return nil
+}
+
+func (g *GRPCSink) Close() error {
+	close(g.closed)
+	g.wg.Wait()
+
+	g.mu.Lock()
+	client := g.client
+	g.client = nil
+	g.mu.Unlock()
+
+	if client != nil {
+		return client.CloseAndRecv()
+	}
+	return nil
+}
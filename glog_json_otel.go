@@ -0,0 +1,28 @@
+// +build otel
+
+package glog
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() {
+	SetContextExtractor(otelContextExtractor)
+}
+
+// otelContextExtractor emits the W3C Trace Context fields Loki/Tempo/
+// Jaeger expect for correlating a log line with its span. It is only
+// compiled in with -tags otel so the default build stays dependency-free.
+func otelContextExtractor(ctx context.Context) []Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []Field{
+		Str("trace_id", sc.TraceID().String()),
+		Str("span_id", sc.SpanID().String()),
+		Str("trace_flags", sc.TraceFlags().String()),
+	}
+}
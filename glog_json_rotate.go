@@ -0,0 +1,303 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Size/time-based rotation, gzip compression and retention for JSON log
+// files, exposed as a Sink (see glog_json_sink.go) so it slots in next to
+// fileSink/bufferedSink/GRPCSink without the core package needing a
+// lumberjack-style shim bolted on from outside.
+
+package glog
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	logMaxSize    = flag.Int64("log_max_size", 0, "rotate a JSON log file once it exceeds this many bytes; 0 disables size-based rotation")
+	logMaxAgeFlag = flag.Duration("log_max_age", 0, "rotate a JSON log file once it has been open this long; 0 disables time-based rotation")
+	logMaxBackups = flag.Int("log_max_backups", 0, "number of rotated JSON log segments to retain; 0 sets no count limit (-log_max_age still prunes older segments)")
+	logCompress   = flag.Bool("log_compress", false, "gzip-compress rotated JSON log segments")
+)
+
+// rotatingWriter wraps a single severity's log file the way syncBuffer
+// does, but rotates it by size and/or age, optionally gzipping the
+// rotated segment and pruning old ones.
+type rotatingWriter struct {
+	dir        string
+	name       string // base file name, e.g. "myapp.INFO"
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func newRotatingWriter(dir, name string, maxSize int64, maxAge time.Duration, maxBackups int, compress bool) *rotatingWriter {
+	return &rotatingWriter{
+		dir:        dir,
+		name:       name,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		compress:   compress,
+	}
+}
+
+func (w *rotatingWriter) path() string {
+	return filepath.Join(w.dir, w.name)
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.openLocked(); err != nil {
+			return 0, err
+		}
+	} else if w.needsRotateLocked() {
+		if err := w.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingWriter) needsRotateLocked() bool {
+	if w.maxSize > 0 && w.size >= w.maxSize {
+		return true
+	}
+	if w.maxAge > 0 && timeNow().Sub(w.openedAt) >= w.maxAge {
+		return true
+	}
+	return false
+}
+
+func (w *rotatingWriter) openLocked() error {
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = timeNow()
+	return nil
+}
+
+func (w *rotatingWriter) rotateLocked() error {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	backup := fmt.Sprintf("%s.%s", w.path(), timeNow().Format("20060102-150405.000000"))
+	if err := os.Rename(w.path(), backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if w.compress {
+		go compressAndRemove(backup)
+	}
+	go w.pruneBackups()
+	return w.openLocked()
+}
+
+// Reopen closes and reopens the underlying file, for use from a SIGHUP
+// handler so an external logrotate can move the file out from under us.
+func (w *rotatingWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	return w.openLocked()
+}
+
+func (w *rotatingWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func compressAndRemove(path string) {
+	in, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err == nil {
+		gz.Close()
+		out.Close()
+		os.Remove(path)
+	} else {
+		gz.Close()
+		out.Close()
+		os.Remove(path + ".gz")
+	}
+}
+
+// pruneBackups removes rotated segments beyond maxBackups and older than
+// maxAge, newest first.
+func (w *rotatingWriter) pruneBackups() {
+	matches, err := filepath.Glob(w.path() + ".*")
+	if err != nil {
+		return
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+
+	for i, m := range matches {
+		remove := false
+		if w.maxBackups > 0 && i >= w.maxBackups {
+			remove = true
+		}
+		if w.maxAge > 0 {
+			if info, err := os.Stat(m); err == nil && timeNow().Sub(info.ModTime()) > w.maxAge {
+				remove = true
+			}
+		}
+		if remove {
+			os.Remove(m)
+		}
+	}
+}
+
+// RotatingFileSink is a Sink (see glog_json_sink.go) that writes each
+// severity's entries to its own rotatingWriter, cascading the way
+// fileSink does so the INFO file also contains WARNING/ERROR/FATAL
+// lines.
+type RotatingFileSink struct {
+	writers []*rotatingWriter // indexed by severity
+}
+
+// NewRotatingFileSink builds a RotatingFileSink writing "<prefix>.<SEVERITY>"
+// files under dir, honoring -log_max_size/-log_max_age/-log_max_backups/
+// -log_compress.
+func NewRotatingFileSink(dir, prefix string) *RotatingFileSink {
+	writers := make([]*rotatingWriter, len(lowerSeverityName))
+	for s, name := range lowerSeverityName {
+		writers[s] = newRotatingWriter(dir, prefix+"."+strings.ToUpper(name), *logMaxSize, *logMaxAgeFlag, *logMaxBackups, *logCompress)
+	}
+	sink := &RotatingFileSink{writers: writers}
+	registerRotatingSink(sink)
+	return sink
+}
+
+func (r *RotatingFileSink) Write(sev severity, entry []byte) error {
+	var firstErr error
+	for s := sev; s >= infoLog; s-- {
+		if _, err := r.writers[s].Write(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *RotatingFileSink) Flush() error {
+	var firstErr error
+	for _, w := range r.writers {
+		if err := w.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *RotatingFileSink) Close() error {
+	var firstErr error
+	for _, w := range r.writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *RotatingFileSink) reopen() {
+	for _, w := range r.writers {
+		w.Reopen()
+	}
+}
+
+var (
+	rotatingSinksMu sync.Mutex
+	rotatingSinks   []*RotatingFileSink
+	sighupOnce      sync.Once
+)
+
+// registerRotatingSink tracks sink so the SIGHUP handler (see
+// glog_json_rotate_unix.go / glog_json_rotate_windows.go) can reopen it,
+// and starts that handler on first use.
+func registerRotatingSink(sink *RotatingFileSink) {
+	rotatingSinksMu.Lock()
+	rotatingSinks = append(rotatingSinks, sink)
+	rotatingSinksMu.Unlock()
+
+	sighupOnce.Do(func() {
+		watchSighup(reopenAllRotatingSinks)
+	})
+}
+
+func reopenAllRotatingSinks() {
+	rotatingSinksMu.Lock()
+	sinks := append([]*RotatingFileSink(nil), rotatingSinks...)
+	rotatingSinksMu.Unlock()
+	for _, s := range sinks {
+		s.reopen()
+	}
+}
@@ -0,0 +1,115 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingWriterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	w := newRotatingWriter(dir, "test.INFO", 10, 0, 0, false)
+	defer w.Close()
+
+	// Write checks needsRotateLocked only at the start of a call, so
+	// exceeding maxSize doesn't rotate until the *next* Write after that.
+	if _, err := w.Write([]byte("12345")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("67890ab")); err != nil { // size now 12, over maxSize
+		t.Fatal(err)
+	}
+
+	matches, err := filepath.Glob(w.path() + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("should not rotate before the next write observes the over-limit size, got %d: %v", len(matches), matches)
+	}
+
+	if _, err := w.Write([]byte("x")); err != nil { // triggers the deferred rotation
+		t.Fatal(err)
+	}
+
+	matches, err = filepath.Glob(w.path() + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected one rotated backup after a write observed the over-limit size, got %d: %v", len(matches), matches)
+	}
+}
+
+func TestRotatingWriterPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	w := newRotatingWriter(dir, "test.INFO", 0, 0, 2, false)
+
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("%s.2026010%d-000000.000000", w.path(), i)
+		if err := os.WriteFile(name, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.pruneBackups()
+
+	matches, err := filepath.Glob(w.path() + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected maxBackups=2 to retain 2 backups, got %d: %v", len(matches), matches)
+	}
+}
+
+func TestCompressAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "seg.log")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	compressAndRemove(path)
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("original segment should be removed after compression, stat err: %v", err)
+	}
+
+	gz, err := os.Open(path + ".gz")
+	if err != nil {
+		t.Fatalf("expected gzipped segment: %v", err)
+	}
+	defer gz.Close()
+
+	r, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Fatalf("got %q, want %q", data, "hello world")
+	}
+}
@@ -0,0 +1,21 @@
+// +build !windows
+
+package glog
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchSighup calls reopen every time the process receives SIGHUP, so an
+// external logrotate can move rotated files out from under glog.
+func watchSighup(reopen func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			reopen()
+		}
+	}()
+}
@@ -0,0 +1,7 @@
+// +build windows
+
+package glog
+
+// Windows has no SIGHUP; RotatingFileSink files are only ever reopened
+// through an explicit Reopen call on that platform.
+func watchSighup(reopen func()) {}
@@ -0,0 +1,217 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Sampling and rate limiting for JSON log entries, so a hot call site
+// hitting JError() in a tight loop can't flood the sink.
+
+package glog
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampler decides whether the entry about to be built at severity s,
+// from file:line, should go through at all. It runs in json(), before
+// jheader does any formatting, so a rejected entry costs only the
+// Allow call.
+type Sampler interface {
+	Allow(s severity, file string, line int) bool
+}
+
+var (
+	samplerMu        sync.Mutex
+	activeSampler    Sampler
+	samplerSetByUser bool
+	resolveSampOne   sync.Once
+
+	logRate = flag.String("log_rate", "", "token-bucket rate limit for JSON log entries per severity, e.g. \"100/s\"; empty disables rate limiting")
+
+	// sampleDropped counts entries a Sampler rejected, indexed the same
+	// way as lowerSeverityName. glog_json.go's severityStats tracks
+	// lines actually written; this is its rejected-entry counterpart.
+	sampleDropped = make([]int64, len(lowerSeverityName))
+)
+
+// SetSampler installs s as the active sampler. Passing nil disables
+// sampling (the default).
+func SetSampler(s Sampler) {
+	samplerMu.Lock()
+	defer samplerMu.Unlock()
+	activeSampler = s
+	samplerSetByUser = true
+}
+
+func getSampler() Sampler {
+	resolveSampOne.Do(func() {
+		if *logRate == "" {
+			return
+		}
+		if n, ok := parseRate(*logRate); ok {
+			samplerMu.Lock()
+			if !samplerSetByUser {
+				activeSampler = NewTokenBucketSampler(n)
+			}
+			samplerMu.Unlock()
+		}
+	})
+	samplerMu.Lock()
+	defer samplerMu.Unlock()
+	return activeSampler
+}
+
+func parseRate(s string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "/s"))
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// SampleDropped returns the number of entries dropped by the active
+// Sampler for severity s so far.
+func SampleDropped(s severity) int64 {
+	return atomic.LoadInt64(&sampleDropped[s])
+}
+
+// sampledHeader is json()'s replacement for calling jheader directly: it
+// consults the active Sampler and, if the entry is rejected, counts the
+// drop and returns nil instead of building a header.
+//
+// Fatal entries always bypass the sampler: outputj's process-exit logic
+// only runs once a Json reaches it, so letting a Sampler reject a fatal
+// entry would make JFatal/JFatalw/JFatalCtx silently vanish without
+// logging anything or exiting the process, breaking glog's Fatal
+// contract.
+func sampledHeader(s severity, level Level, file string, line int) *Json {
+	if s == fatalLog {
+		return jheader(s, level, file, line)
+	}
+	if sampler := getSampler(); sampler != nil && !sampler.Allow(s, file, line) {
+		atomic.AddInt64(&sampleDropped[s], 1)
+		return nil
+	}
+	return jheader(s, level, file, line)
+}
+
+// tokenBucket is a simple per-severity token bucket: ratePerSec tokens
+// are added every second, capped at a burst of ratePerSec, and each
+// Allow call consumes one.
+type tokenBucket struct {
+	ratePerSec float64
+
+	mu       sync.Mutex
+	tokens   float64
+	lastFill time.Time
+}
+
+// TokenBucketSampler rate-limits each severity independently at
+// ratePerSec entries/second, so a spike in one severity doesn't use up
+// another's budget.
+type TokenBucketSampler struct {
+	buckets []*tokenBucket
+}
+
+// NewTokenBucketSampler builds a TokenBucketSampler allowing up to
+// ratePerSec entries/second for each severity.
+func NewTokenBucketSampler(ratePerSec int) *TokenBucketSampler {
+	buckets := make([]*tokenBucket, len(lowerSeverityName))
+	for i := range buckets {
+		buckets[i] = &tokenBucket{ratePerSec: float64(ratePerSec), tokens: float64(ratePerSec)}
+	}
+	return &TokenBucketSampler{buckets: buckets}
+}
+
+func (t *TokenBucketSampler) Allow(s severity, file string, line int) bool {
+	b := t.buckets[s]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := timeNow()
+	if !b.lastFill.IsZero() {
+		elapsed := now.Sub(b.lastFill).Seconds()
+		b.tokens += elapsed * b.ratePerSec
+		if b.tokens > b.ratePerSec {
+			b.tokens = b.ratePerSec
+		}
+	}
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// burstThenTailCounter tracks one (severity, file, line) call site's
+// count within the current one-second window.
+type burstThenTailCounter struct {
+	windowStart time.Time
+	count       int64
+}
+
+// BurstThenTailSampler logs the first Initial entries per call site per
+// second, then every Thereafter-th entry after that, the zap/klog
+// "burst then tail" strategy: a spammy call site is throttled without
+// silencing rarer ones elsewhere.
+type BurstThenTailSampler struct {
+	Initial    int64
+	Thereafter int64
+
+	mu       sync.Mutex
+	counters map[string]*burstThenTailCounter
+}
+
+// NewBurstThenTailSampler builds a BurstThenTailSampler that allows the
+// first initial entries from a call site each second, then every
+// thereafter-th one.
+func NewBurstThenTailSampler(initial, thereafter int64) *BurstThenTailSampler {
+	return &BurstThenTailSampler{
+		Initial:    initial,
+		Thereafter: thereafter,
+		counters:   make(map[string]*burstThenTailCounter),
+	}
+}
+
+func (b *BurstThenTailSampler) Allow(s severity, file string, line int) bool {
+	key := fmt.Sprintf("%d:%s:%d", s, file, line)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := timeNow()
+	c, ok := b.counters[key]
+	if !ok || now.Sub(c.windowStart) >= time.Second {
+		c = &burstThenTailCounter{windowStart: now}
+		b.counters[key] = c
+	}
+	c.count++
+
+	if c.count <= b.Initial {
+		return true
+	}
+	over := c.count - b.Initial
+	if b.Thereafter <= 0 {
+		return false
+	}
+	return over%b.Thereafter == 0
+}
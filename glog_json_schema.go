@@ -0,0 +1,200 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Schema controls the field names jheader writes for each JSON log
+// entry, so output can be consumed directly by a downstream system
+// instead of needing a post-processing transform.
+
+package glog
+
+import (
+	"flag"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Schema builds the opening object of a JSON log entry (everything up to
+// but not including the message field) and names the message field
+// itself. The returned header must start with '{' and end without a
+// trailing comma, ready for more ,"field":value appends.
+type Schema interface {
+	Header(s severity, level Level, file string, line int, now time.Time) []byte
+	MessageKey() string
+}
+
+// SchemaGlog is the default Schema: selecting it (or leaving
+// -log_schema unset) keeps jheader's existing hand-rolled fast path, so
+// the default encoding pays no extra cost for the Schema indirection.
+type SchemaGlog struct{}
+
+func (SchemaGlog) Header(s severity, level Level, file string, line int, now time.Time) []byte {
+	// jheader special-cases SchemaGlog itself; Header is never called
+	// for it, but it must still satisfy the interface.
+	return nil
+}
+
+func (SchemaGlog) MessageKey() string { return "message" }
+
+// SchemaECS follows the Elastic Common Schema field names used by
+// Filebeat/Logstash pipelines that feed Elasticsearch.
+type SchemaECS struct{}
+
+func (SchemaECS) Header(s severity, level Level, file string, line int, now time.Time) []byte {
+	p := make([]byte, 0, 160)
+	p = append(p, "{\"@timestamp\":\""...)
+	p = append(p, now.Format(time.RFC3339Nano)...)
+	p = append(p, "\",\"log.level\":\""...)
+	p = append(p, lowerSeverityName[s]...)
+	p = append(p, "\",\"host.hostname\":\""...)
+	p = append(p, host...)
+	p = append(p, "\",\"process.pid\":"...)
+	p = strconv.AppendInt(p, int64(pid), 10)
+	p = append(p, ",\"log.origin.file.name\":\""...)
+	p = append(p, file...)
+	p = append(p, "\",\"log.origin.file.line\":"...)
+	p = strconv.AppendInt(p, int64(line), 10)
+	return p
+}
+
+func (SchemaECS) MessageKey() string { return "message" }
+
+// SchemaGELF follows the Graylog Extended Log Format consumed by Graylog
+// inputs over UDP/TCP/HTTP.
+type SchemaGELF struct{}
+
+var gelfSyslogLevel = []int{
+	infoLog:    6,
+	warningLog: 4,
+	errorLog:   3,
+	fatalLog:   2,
+}
+
+func (SchemaGELF) Header(s severity, level Level, file string, line int, now time.Time) []byte {
+	p := make([]byte, 0, 160)
+	p = append(p, "{\"version\":\"1.1\",\"host\":\""...)
+	p = append(p, host...)
+	p = append(p, "\",\"timestamp\":"...)
+	p = strconv.AppendFloat(p, float64(now.UnixNano())/1e9, 'f', 3, 64)
+	p = append(p, ",\"level\":"...)
+	p = strconv.AppendInt(p, int64(gelfSyslogLevel[s]), 10)
+	p = append(p, ",\"_file\":\""...)
+	p = append(p, file...)
+	p = append(p, "\",\"_line\":"...)
+	p = strconv.AppendInt(p, int64(line), 10)
+	return p
+}
+
+func (SchemaGELF) MessageKey() string { return "short_message" }
+
+// SchemaStackdriver follows the structured JSON fields Google Cloud
+// Logging's agent parses out of stdout/stderr automatically.
+type SchemaStackdriver struct{}
+
+// stackdriverSeverity maps glog's severities onto Cloud Logging's
+// LogSeverity enum. It is its own table rather than
+// strings.ToUpper(lowerSeverityName[s]) because glog's "fatal" has no
+// matching Cloud Logging value; an unrecognized string falls back to
+// DEFAULT, which would silently drop the severity that matters most.
+var stackdriverSeverity = []string{
+	infoLog:    "INFO",
+	warningLog: "WARNING",
+	errorLog:   "ERROR",
+	fatalLog:   "CRITICAL",
+}
+
+func (SchemaStackdriver) Header(s severity, level Level, file string, line int, now time.Time) []byte {
+	p := make([]byte, 0, 192)
+	p = append(p, "{\"severity\":\""...)
+	p = append(p, stackdriverSeverity[s]...)
+	p = append(p, "\",\"time\":\""...)
+	p = append(p, now.Format(time.RFC3339Nano)...)
+	p = append(p, "\",\"logging.googleapis.com/sourceLocation\":{\"file\":\""...)
+	p = append(p, file...)
+	p = append(p, "\",\"line\":\""...)
+	p = strconv.AppendInt(p, int64(line), 10)
+	p = append(p, '"', '}')
+	return p
+}
+
+func (SchemaStackdriver) MessageKey() string { return "message" }
+
+var schemas = map[string]Schema{
+	"glog":        SchemaGlog{},
+	"ecs":         SchemaECS{},
+	"gelf":        SchemaGELF{},
+	"stackdriver": SchemaStackdriver{},
+}
+
+var logSchemaFlag = flag.String("log_schema", "glog", "JSON field schema for log entries: glog, ecs, gelf, or stackdriver")
+
+var (
+	schemaMu          sync.Mutex
+	selectedSchema    Schema
+	schemaSetByUser   bool
+	resolveSchemaOnce sync.Once
+)
+
+// SetSchema overrides -log_schema programmatically. Passing nil (or
+// SchemaGlog{}) restores the default fast path. Once called, -log_schema's
+// own lazy resolution (on the first log call) no longer overrides the
+// caller's choice.
+//
+// SchemaGlog{} is a zero-size struct, so it is a non-nil Schema value;
+// it is normalized to nil here so jheader's `sc != nil` check can't be
+// fooled into calling SchemaGlog.Header (which deliberately returns nil,
+// since jheader never actually calls it for the real default path).
+func SetSchema(s Schema) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	if _, ok := s.(SchemaGlog); ok {
+		s = nil
+	}
+	selectedSchema = s
+	schemaSetByUser = true
+}
+
+// activeSchema applies -log_schema the first time it's consulted after
+// flags have been parsed, then returns the active Schema (nil for the
+// default fast path). It does nothing if SetSchema was already called
+// explicitly, so that call's result can't be clobbered later.
+func activeSchema() Schema {
+	if flag.Parsed() {
+		resolveSchemaOnce.Do(func() {
+			schemaMu.Lock()
+			alreadySet := schemaSetByUser
+			schemaMu.Unlock()
+			if alreadySet {
+				return
+			}
+
+			name := *logSchemaFlag
+			if sc, ok := schemas[name]; ok && name != "glog" {
+				SetSchema(sc)
+			}
+		})
+	}
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	return selectedSchema
+}
+
+func messageKey() string {
+	if sc := activeSchema(); sc != nil {
+		return sc.MessageKey()
+	}
+	return "message"
+}
@@ -0,0 +1,42 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSetSchemaGlogRestoresFastPath(t *testing.T) {
+	SetSchema(SchemaECS{})
+	if activeSchema() == nil {
+		t.Fatal("SetSchema(SchemaECS{}) should make activeSchema non-nil")
+	}
+
+	SetSchema(SchemaGlog{})
+	if sc := activeSchema(); sc != nil {
+		t.Fatalf("SetSchema(SchemaGlog{}) should restore the nil fast path, got %#v", sc)
+	}
+}
+
+func TestSchemaStackdriverFatalSeverity(t *testing.T) {
+	h := SchemaStackdriver{}.Header(fatalLog, 0, "f.go", 1, time.Time{})
+	if !bytes.Contains(h, []byte(`"severity":"CRITICAL"`)) {
+		t.Fatalf("want a valid Cloud Logging severity for fatalLog, got %s", h)
+	}
+}
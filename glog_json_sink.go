@@ -0,0 +1,258 @@
+// Go support for leveled logs, analogous to https://code.google.com/p/google-glog/
+//
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Pluggable output sinks for JSON log entries.
+
+package glog
+
+import (
+	"errors"
+	"flag"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink receives encoded JSON log entries produced by outputj. Sinks must
+// be safe for concurrent use; outputj calls Write while holding
+// loggingT.mu, so a slow sink stalls all logging until it returns.
+type Sink interface {
+	Write(sev severity, entry []byte) error
+	Flush() error
+	Close() error
+}
+
+var (
+	sinkRegistryMu sync.Mutex
+	sinkRegistry   = map[string]Sink{}
+
+	activeSinkMu  sync.Mutex
+	activeSink    Sink
+	sinkSetByUser bool
+
+	logSink = flag.String("log_sink", "", "name of a registered Sink to ship JSON log entries to instead of the default per-severity log files; stderr output is unchanged")
+)
+
+// RegisterSink makes a Sink available under name for selection via
+// -log_sink. It is typically called from an init function by code that
+// wires up a concrete Sink (e.g. the gRPC sink in glog_json_grpcsink.go).
+func RegisterSink(name string, s Sink) {
+	sinkRegistryMu.Lock()
+	defer sinkRegistryMu.Unlock()
+	sinkRegistry[name] = s
+}
+
+// SetSink installs s as the active sink, overriding whatever -log_sink
+// selected. Passing nil restores the default file/stderr-only behavior.
+// Once called, -log_sink's own lazy resolution (on the first log call)
+// no longer overrides the caller's choice.
+func SetSink(s Sink) {
+	activeSinkMu.Lock()
+	defer activeSinkMu.Unlock()
+	activeSink = s
+	sinkSetByUser = true
+}
+
+var resolveSinkOnce sync.Once
+
+// resolveSink applies -log_sink the first time a log entry is emitted
+// after flags have been parsed, mirroring the lazy flag.Parsed() check
+// outputj already performs. It does nothing if SetSink was already
+// called explicitly, so that call's result can't be clobbered later.
+func resolveSink() {
+	if !flag.Parsed() {
+		return
+	}
+	resolveSinkOnce.Do(func() {
+		activeSinkMu.Lock()
+		alreadySet := sinkSetByUser
+		activeSinkMu.Unlock()
+		if alreadySet {
+			return
+		}
+
+		name := *logSink
+		if name == "" {
+			return
+		}
+		sinkRegistryMu.Lock()
+		s, ok := sinkRegistry[name]
+		sinkRegistryMu.Unlock()
+		if !ok {
+			return
+		}
+		SetSink(s)
+	})
+}
+
+func currentSink() Sink {
+	activeSinkMu.Lock()
+	defer activeSinkMu.Unlock()
+	return activeSink
+}
+
+// fileSink reproduces the pre-Sink behavior of outputj: the entry is
+// appended to the log file for sev and every lower severity, so that the
+// INFO file also contains WARNING, ERROR and FATAL lines.
+type fileSink struct {
+	l *loggingT
+}
+
+// Write matches the baseline's cascading behavior: only a createFiles
+// failure is reported to the caller (outputj writes entry to stderr
+// itself, then exits for this specific, default-sink failure, mirroring
+// the pre-Sink code). An ordinary per-file Write error is swallowed, as
+// it always was before the Sink refactor, rather than treated as fatal.
+func (f *fileSink) Write(sev severity, entry []byte) error {
+	if f.l.file[sev] == nil {
+		if err := f.l.createFiles(sev); err != nil {
+			return err
+		}
+	}
+	for s := sev; s >= infoLog; s-- {
+		f.l.file[s].Write(entry)
+	}
+	return nil
+}
+
+func (f *fileSink) Flush() error {
+	var firstErr error
+	for s := fatalLog; s >= infoLog; s-- {
+		if f.l.file[s] == nil {
+			continue
+		}
+		if err := f.l.file[s].Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (f *fileSink) Close() error {
+	var firstErr error
+	for s := fatalLog; s >= infoLog; s-- {
+		if f.l.file[s] == nil {
+			continue
+		}
+		if err := f.l.file[s].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ErrSinkFull is returned, and counted, when a bufferedSink's queue is
+// saturated and the entry is dropped rather than blocking the caller.
+var ErrSinkFull = errors.New("glog: sink buffer full, entry dropped")
+
+// bufferedSink wraps another Sink with a bounded channel so that a slow
+// downstream (typically a network sink) never blocks the logging path.
+// Entries are dropped, not queued, once the buffer is full.
+type bufferedSink struct {
+	next    Sink
+	entries chan bufferedEntry
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	dropped int64
+}
+
+type bufferedEntry struct {
+	sev   severity
+	entry []byte
+}
+
+// NewBufferedSink starts a background goroutine that drains into next,
+// buffering up to capacity entries before it starts dropping them.
+func NewBufferedSink(next Sink, capacity int) *bufferedSink {
+	b := &bufferedSink{
+		next:    next,
+		entries: make(chan bufferedEntry, capacity),
+		done:    make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.loop()
+	return b
+}
+
+func (b *bufferedSink) loop() {
+	defer b.wg.Done()
+	for {
+		select {
+		case e := <-b.entries:
+			b.next.Write(e.sev, e.entry)
+		case <-b.done:
+			for {
+				select {
+				case e := <-b.entries:
+					b.next.Write(e.sev, e.entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *bufferedSink) Write(sev severity, entry []byte) error {
+	cp := make([]byte, len(entry))
+	copy(cp, entry)
+	select {
+	case b.entries <- bufferedEntry{sev: sev, entry: cp}:
+		return nil
+	default:
+		atomic.AddInt64(&b.dropped, 1)
+		return ErrSinkFull
+	}
+}
+
+// Dropped returns the number of entries discarded so far because the
+// buffer was full.
+func (b *bufferedSink) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
+func (b *bufferedSink) Flush() error { return b.next.Flush() }
+
+func (b *bufferedSink) Close() error {
+	close(b.done)
+	b.wg.Wait()
+	return b.next.Close()
+}
+
+// backoff is the minimal reconnect schedule shared by network sinks:
+// it doubles delay up to max, with no jitter since entries are already
+// batched and rare reconnects don't need spreading.
+type backoff struct {
+	base, max time.Duration
+	cur       time.Duration
+}
+
+func (b *backoff) next() time.Duration {
+	if b.cur == 0 {
+		b.cur = b.base
+	}
+	d := b.cur
+	b.cur *= 2
+	if b.cur > b.max {
+		b.cur = b.max
+	}
+	return d
+}
+
+func (b *backoff) reset() {
+	b.cur = 0
+}